@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// ctxKey is an unexported type to avoid collisions with context keys
+// defined in other packages.
+type ctxKey int
+
+// claimsKey is the context.Context key under which Authenticate stores the
+// request's Claims.
+const claimsKey ctxKey = 1
+
+// ClaimsFromContext recovers the Claims stored by Authenticate. The second
+// return value is false if the request was never authenticated.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(Claims)
+	return claims, ok
+}
+
+// NewContext returns a copy of ctx carrying claims, retrievable via
+// ClaimsFromContext. Authenticate uses this to thread claims through a
+// request's context; it's also the way non-HTTP callers (tests, other
+// internal packages) can set up a context as if a request had already been
+// authenticated.
+func NewContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// Authenticate validates the bearer token on the request's Authorization
+// header and, if valid, stores the resulting Claims on the request context
+// for downstream handlers. It responds 401 if the header is missing or the
+// token doesn't verify.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		parts := strings.Split(header, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			http.Error(w, "expected authorization header format: Bearer <token>", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := a.ParseClaims(parts[1])
+		if err != nil {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := NewContext(r.Context(), claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// HasRole returns middleware that 403s any request whose Claims (as set by
+// Authenticate) don't include one of the given roles.
+func HasRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok || !claims.HasRole(roles...) {
+				http.Error(w, "you are not authorized for that action", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// Package events defines the domain-event envelope emitted by services in
+// this API and a pluggable Publisher for delivering them, so downstream
+// consumers (welcome emails, cache invalidation, search indexing, audit
+// logs) can react without polling the database.
+package events
+
+import "context"
+
+// Event is a domain event describing something that happened to an entity.
+type Event struct {
+	Type          string                 `json:"type"`
+	EntityID      string                 `json:"entity_id"`
+	Timestamp     int64                  `json:"timestamp"`
+	Actor         string                 `json:"actor"`
+	ChangedFields []string               `json:"changed_fields,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+}
+
+// Publisher delivers Events to whatever downstream consumers are
+// listening.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// NoopPublisher discards every event. It's the default Publisher so
+// services work without one configured.
+type NoopPublisher struct{}
+
+// Publish implements Publisher by doing nothing.
+func (NoopPublisher) Publish(ctx context.Context, event Event) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// NATSPublisher publishes events as JSON to a NATS subject derived from the
+// event's Type (e.g. "user.created").
+type NATSPublisher struct {
+	conn *nats.Conn
+}
+
+// NewNATSPublisher connects to the NATS server at url and returns a
+// Publisher backed by it.
+func NewNATSPublisher(url string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, errors.Wrap(err, "connecting to nats")
+	}
+
+	return &NATSPublisher{conn: conn}, nil
+}
+
+// Publish delivers event on the subject named by event.Type.
+func (p *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "marshalling event")
+	}
+
+	return errors.Wrap(p.conn.Publish(event.Type, data), "publishing event")
+}
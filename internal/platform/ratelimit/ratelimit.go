@@ -0,0 +1,102 @@
+// Package ratelimit provides a simple per-key fixed-window rate limiter,
+// used as HTTP middleware on endpoints that could otherwise be used to
+// enumerate accounts (login, password reset) or brute-force credentials.
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many Allow calls accumulate between sweeps of expired
+// windows, so a Limiter doesn't hold a *window forever for a key that never
+// comes back.
+const sweepEvery = 1000
+
+// window tracks how many requests a key has made in the current fixed
+// window.
+type window struct {
+	start time.Time
+	count int
+}
+
+// Limiter allows up to Limit requests per key every Window.
+type Limiter struct {
+	Limit  int
+	Window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*window
+	ops     int
+}
+
+// New constructs a Limiter allowing limit requests per key every window.
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		Limit:   limit,
+		Window:  window,
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow reports whether key may make another request in the current
+// window, recording the attempt either way.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	l.ops++
+	if l.ops >= sweepEvery {
+		l.ops = 0
+		l.sweep(now)
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) > l.Window {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	w.count++
+	return w.count <= l.Limit
+}
+
+// sweep deletes windows that have closed (started more than Window ago), so keys that stop
+// making requests don't pin memory in l.windows forever. Callers must hold
+// l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) > l.Window {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// Middleware responds 429 to any request whose key (by default, the
+// client's IP address) has exceeded the limit for the current window.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow(clientIP(r)) {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the request's IP address with any port stripped off, so
+// a client making requests over separate, non-keepalive connections (each
+// with a different ephemeral port in r.RemoteAddr) still gets a stable
+// rate-limit key.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,90 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/service/internal/platform/auth"
+)
+
+// authCtx returns a context as if an HTTP request from subject/role had
+// already passed through auth.Authenticate.
+func authCtx(subject, role string) context.Context {
+	claims := auth.NewClaims(subject, []string{role}, time.Now(), time.Hour)
+	return auth.NewContext(context.Background(), claims)
+}
+
+const ownerID = "111111111111111111111111"
+const otherID = "222222222222222222222222"
+const adminID = "333333333333333333333333"
+
+func TestAuthorizationMatrix(t *testing.T) {
+	owner := &User{UserID: ownerID, Role: RoleUser, Email: "owner@example.com"}
+	svc := NewService(newMemoryRepository(owner), nil)
+
+	cases := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{"admin", authCtx(adminID, RoleAdmin), nil},
+		{"owner", authCtx(ownerID, RoleUser), nil},
+		{"other", authCtx(otherID, RoleUser), ErrForbidden},
+	}
+
+	t.Run("Retrieve", func(t *testing.T) {
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := svc.Retrieve(tc.ctx, ownerID)
+				if err != tc.wantErr {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+			})
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				company := "irrelevant"
+				patch := &UpdateUser{Company: &company}
+				err := svc.Update(tc.ctx, ownerID, patch, time.Now())
+				if err != tc.wantErr {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+			})
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		// Delete removes its target, so each case needs its own fixture
+		// rather than sharing the owner/svc set up above.
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				caseSvc := NewService(newMemoryRepository(&User{UserID: ownerID, Role: RoleUser, Email: "owner@example.com"}), nil)
+
+				err := caseSvc.Delete(tc.ctx, ownerID)
+				if err != tc.wantErr {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				if tc.wantErr == nil {
+					if _, err := caseSvc.Repo.Retrieve(context.Background(), ownerID); err != ErrNotFound {
+						t.Fatalf("expected user to be deleted, got error %v", err)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestListRequiresAdmin(t *testing.T) {
+	svc := NewService(newMemoryRepository(&User{UserID: ownerID, Role: RoleUser}), nil)
+
+	if _, err := svc.List(authCtx(ownerID, RoleUser), ListOptions{}); err != ErrForbidden {
+		t.Fatalf("got error %v, want %v", err, ErrForbidden)
+	}
+	if _, err := svc.List(authCtx(adminID, RoleAdmin), ListOptions{}); err != nil {
+		t.Fatalf("admin list: got error %v, want nil", err)
+	}
+}
@@ -0,0 +1,72 @@
+package user
+
+import "net/http"
+
+// Error is a typed domain error carrying the HTTP status the web layer
+// should respond with, so handlers don't need to know which package-level
+// sentinel maps to which status code.
+type Error struct {
+	msg    string
+	status int
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// HTTPStatus returns the HTTP status code this error should be reported as.
+func (e *Error) HTTPStatus() int {
+	return e.status
+}
+
+// The error taxonomy returned by this package. Handlers should use
+// HTTPStatus to translate one of these (possibly wrapped via
+// github.com/pkg/errors) into a response code, rather than defaulting
+// everything to 500.
+var (
+	// ErrNotFound occurs when a user lookup matches no document.
+	ErrNotFound = &Error{"Entity not found", http.StatusNotFound}
+
+	// ErrInvalidID occurs when an ID is not in its proper form.
+	ErrInvalidID = &Error{"ID is not in its proper form", http.StatusBadRequest}
+
+	// ErrDuplicateEmail occurs when Create or Update would violate the
+	// unique index on email.
+	ErrDuplicateEmail = &Error{"Email address is already in use", http.StatusConflict}
+
+	// ErrValidation occurs when the caller-supplied data fails validation.
+	ErrValidation = &Error{"Validation failed", http.StatusBadRequest}
+
+	// ErrConflict occurs when an operation can't be applied to a user's
+	// current state.
+	ErrConflict = &Error{"Request conflicts with the current state", http.StatusConflict}
+
+	// ErrForbidden occurs when the caller isn't permitted to perform the
+	// requested action.
+	ErrForbidden = &Error{"Action not permitted", http.StatusForbidden}
+)
+
+// causer matches github.com/pkg/errors' Cause() so HTTPStatus can see
+// through wrapped errors without importing the package just for that.
+type causer interface {
+	Cause() error
+}
+
+// HTTPStatus unwraps err (if it was wrapped with errors.Wrap) and returns
+// the status code it should be reported to a client as. Errors outside this
+// package's taxonomy map to 500.
+func HTTPStatus(err error) int {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e.HTTPStatus()
+		}
+		c, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = c.Cause()
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,81 @@
+package user
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	mgo "gopkg.in/mgo.v2"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"ErrNotFound", ErrNotFound, http.StatusNotFound},
+		{"ErrInvalidID", ErrInvalidID, http.StatusBadRequest},
+		{"ErrDuplicateEmail", ErrDuplicateEmail, http.StatusConflict},
+		{"ErrValidation", ErrValidation, http.StatusBadRequest},
+		{"ErrConflict", ErrConflict, http.StatusConflict},
+		{"ErrForbidden", ErrForbidden, http.StatusForbidden},
+		{"wrapped taxonomy error", errors.Wrap(ErrValidation, "current password is incorrect"), http.StatusBadRequest},
+		{"double-wrapped taxonomy error", errors.Wrap(errors.Wrap(ErrNotFound, "inner"), "outer"), http.StatusNotFound},
+		{"unrecognized error", errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := HTTPStatus(tc.err); got != tc.want {
+				t.Fatalf("got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestIsUniqueViolation exercises the check PostgresRepository.Create and
+// Update use to turn a duplicate email into ErrDuplicateEmail.
+func TestIsUniqueViolation(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unique_violation", &pq.Error{Code: "23505"}, true},
+		{"a different postgres error code", &pq.Error{Code: "23503"}, false},
+		{"not a pq.Error", errors.New("boom"), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isUniqueViolation(tc.err); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestMongoDuplicateKeyDetection exercises the mgo.IsDup check
+// MongoRepository.Create and Update use to turn a duplicate email (caught
+// by the unique index ensureIndexes creates) into ErrDuplicateEmail.
+func TestMongoDuplicateKeyDetection(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"duplicate key error", &mgo.QueryError{Code: 11000, Message: "E11000 duplicate key error"}, true},
+		{"a different mongo error code", &mgo.QueryError{Code: 17280, Message: "some other error"}, false},
+		{"not a mongo error", errors.New("boom"), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mgo.IsDup(tc.err); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package user
+
+import (
+	"context"
+	"time"
+)
+
+// memoryRepository is a minimal in-memory UserRepository fake used across
+// this package's tests so they can run without a real database.
+type memoryRepository struct {
+	users map[string]*User
+}
+
+func newMemoryRepository(users ...*User) *memoryRepository {
+	m := &memoryRepository{users: make(map[string]*User)}
+	for _, u := range users {
+		m.users[u.UserID] = u
+	}
+	return m
+}
+
+func (m *memoryRepository) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	var users []User
+	for _, u := range m.users {
+		users = append(users, *u)
+	}
+	return &ListResult{Users: users, Total: len(users)}, nil
+}
+
+func (m *memoryRepository) Retrieve(ctx context.Context, userID string) (*User, error) {
+	u, ok := m.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return u, nil
+}
+
+func (m *memoryRepository) Create(ctx context.Context, u *User) error {
+	m.users[u.UserID] = u
+	return nil
+}
+
+func (m *memoryRepository) Update(ctx context.Context, userID string, patch *UpdateUser, now time.Time) error {
+	u, ok := m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if patch.UserType != nil {
+		u.UserType = *patch.UserType
+	}
+	if patch.FirstName != nil {
+		u.FirstName = *patch.FirstName
+	}
+	if patch.LastName != nil {
+		u.LastName = *patch.LastName
+	}
+	if patch.Email != nil {
+		u.Email = *patch.Email
+	}
+	if patch.Password != nil {
+		u.PasswordHash = *patch.Password
+	}
+	if patch.Company != nil {
+		u.Company = *patch.Company
+	}
+	u.DateModified = &now
+
+	return nil
+}
+
+func (m *memoryRepository) UpdateAddresses(ctx context.Context, userID string, addresses []Address, now time.Time) error {
+	u, ok := m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	u.Addresses = addresses
+	u.DateModified = &now
+	return nil
+}
+
+func (m *memoryRepository) Delete(ctx context.Context, userID string) error {
+	if _, ok := m.users[userID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.users, userID)
+	return nil
+}
+
+func (m *memoryRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryRepository) SetPasswordHash(ctx context.Context, userID, passwordHash string) error {
+	u, ok := m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	u.PasswordHash = passwordHash
+	return nil
+}
+
+func (m *memoryRepository) SetPasswordResetToken(ctx context.Context, userID, tokenHash string, expiry time.Time) error {
+	u, ok := m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	u.ResetTokenHash = tokenHash
+	u.ResetTokenExpiry = &expiry
+	return nil
+}
+
+func (m *memoryRepository) GetByResetTokenHash(ctx context.Context, tokenHash string) (*User, error) {
+	for _, u := range m.users {
+		if u.ResetTokenHash == tokenHash {
+			return u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryRepository) CompletePasswordReset(ctx context.Context, userID, passwordHash string) error {
+	u, ok := m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	u.PasswordHash = passwordHash
+	u.ResetTokenHash = ""
+	u.ResetTokenExpiry = nil
+	return nil
+}
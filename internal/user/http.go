@@ -0,0 +1,184 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ardanlabs/service/internal/platform/auth"
+	"github.com/ardanlabs/service/internal/platform/ratelimit"
+)
+
+// ParseListOptions builds a ListOptions from an HTTP request's query
+// parameters. It's the glue the `GET /v1/users` handler uses to translate
+// ?limit=&offset=&sort=&dir=&user_type=&company=&email=&q= into the struct
+// List expects.
+func ParseListOptions(values url.Values) ListOptions {
+	opts := ListOptions{
+		SortBy:  values.Get("sort"),
+		SortDir: values.Get("dir"),
+		Query:   values.Get("q"),
+	}
+
+	if limit, err := strconv.Atoi(values.Get("limit")); err == nil {
+		opts.Limit = limit
+	}
+	if offset, err := strconv.Atoi(values.Get("offset")); err == nil {
+		opts.Offset = offset
+	}
+
+	for _, field := range []string{"user_type", "company", "email"} {
+		if v := values.Get(field); v != "" {
+			if opts.Filter == nil {
+				opts.Filter = make(map[string]string)
+			}
+			opts.Filter[field] = v
+		}
+	}
+
+	return opts
+}
+
+// NewListHandler returns the `GET /v1/users` handler: it parses the
+// request's query parameters into a ListOptions, asks svc for the matching
+// page of users, and writes the ListResult as JSON. The caller must be an
+// admin; svc.List enforces that.
+func NewListHandler(svc *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts := ParseListOptions(r.URL.Query())
+
+		result, err := svc.List(r.Context(), opts)
+		if err != nil {
+			http.Error(w, err.Error(), HTTPStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// NewTokenHandler returns the `POST /v1/users/token` handler: it verifies
+// the email/password given as HTTP Basic auth against svc and, on success,
+// responds with a signed JWT.
+func NewTokenHandler(svc *Service, authenticator *auth.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, password, ok := r.BasicAuth()
+		if !ok {
+			http.Error(w, "must provide email and password in Basic auth", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := svc.Authenticate(r.Context(), email, password, time.Now())
+		if err != nil {
+			if err == ErrNotFound {
+				http.Error(w, "invalid email or password", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "authenticating user", http.StatusInternalServerError)
+			return
+		}
+
+		tkn, err := authenticator.GenerateToken(claims)
+		if err != nil {
+			http.Error(w, "generating token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(struct {
+			Token string `json:"token"`
+		}{Token: tkn})
+	}
+}
+
+// NewChangePasswordHandler returns the `POST /v1/users/password` handler.
+// The caller must be authenticated; it changes the authenticated user's own
+// password.
+func NewChangePasswordHandler(svc *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.ClaimsFromContext(r.Context())
+		if !ok {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			OldPassword string `json:"old_password"`
+			NewPassword string `json:"new_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := svc.ChangePassword(r.Context(), claims.Subject, req.OldPassword, req.NewPassword); err != nil {
+			http.Error(w, err.Error(), HTTPStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// NewRequestPasswordResetHandler returns the
+// `POST /v1/users/password/reset-token` handler, which issues a reset
+// token for an email and hands it to deliver (e.g. to be emailed) rather
+// than returning it in the response. It accepts arbitrary emails, so
+// without a rate limit it can be used to enumerate accounts by timing or to
+// spam users with reset emails: requests are run through limiter before
+// reaching the handler.
+func NewRequestPasswordResetHandler(svc *Service, limiter *ratelimit.Limiter, deliver func(email, token string)) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		token, err := svc.RequestPasswordReset(r.Context(), req.Email)
+		if err != nil {
+			http.Error(w, "requesting password reset", http.StatusInternalServerError)
+			return
+		}
+		if token != "" && deliver != nil {
+			deliver(req.Email, token)
+		}
+
+		// Always respond the same way, whether or not the email matched a
+		// user, so this endpoint can't be used to enumerate accounts.
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	return limiter.Middleware(http.HandlerFunc(handler)).ServeHTTP
+}
+
+// NewResetPasswordHandler returns the `POST /v1/users/password/reset`
+// handler, which completes a reset started by
+// NewRequestPasswordResetHandler. Requests are run through limiter before
+// reaching the handler, to bound token-guessing attempts.
+func NewResetPasswordHandler(svc *Service, limiter *ratelimit.Limiter) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token       string `json:"token"`
+			NewPassword string `json:"new_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := svc.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+			http.Error(w, err.Error(), HTTPStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	return limiter.Middleware(http.HandlerFunc(handler)).ServeHTTP
+}
@@ -0,0 +1,156 @@
+package user
+
+import "time"
+
+// Roles a User can hold. RoleAdmin may act on any user; RoleUser may only
+// act on itself.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// User represents someone with access to our system.
+type User struct {
+	UserID       string     `bson:"user_id" json:"id"`
+	UserType     string     `bson:"type" json:"type"`
+	Role         string     `bson:"role" json:"role"`
+	FirstName    string     `bson:"first_name" json:"first_name"`
+	LastName     string     `bson:"last_name" json:"last_name"`
+	Email        string     `bson:"email" json:"email"`
+	PasswordHash string     `bson:"password_hash" json:"-"`
+	Company      string     `bson:"company,omitempty" json:"company,omitempty"`
+	Addresses    []Address  `bson:"addresses" json:"addresses"`
+	DateCreated  *time.Time `bson:"date_created" json:"date_created"`
+	DateModified *time.Time `bson:"date_modified" json:"date_modified"`
+
+	// ResetTokenHash and ResetTokenExpiry back the password-reset flow; both
+	// are cleared once the reset completes.
+	ResetTokenHash   string     `bson:"reset_token_hash,omitempty" json:"-"`
+	ResetTokenExpiry *time.Time `bson:"reset_token_expiry,omitempty" json:"-"`
+}
+
+// Address represents a physical address associated with a User.
+type Address struct {
+	Type         string     `bson:"type" json:"type"`
+	LineOne      string     `bson:"line_one" json:"line_one"`
+	LineTwo      string     `bson:"line_two,omitempty" json:"line_two,omitempty"`
+	City         string     `bson:"city" json:"city"`
+	State        string     `bson:"state" json:"state"`
+	Zipcode      string     `bson:"zipcode" json:"zipcode"`
+	Phone        string     `bson:"phone,omitempty" json:"phone,omitempty"`
+	DateCreated  *time.Time `bson:"date_created" json:"date_created"`
+	DateModified *time.Time `bson:"date_modified" json:"date_modified"`
+}
+
+// CreateUser contains the information needed to create a new User.
+type CreateUser struct {
+	UserType     string          `json:"type" validate:"required"`
+	FirstName    string          `json:"first_name" validate:"required"`
+	LastName     string          `json:"last_name" validate:"required"`
+	Email        string          `json:"email" validate:"required"`
+	Password     string          `bson:"password_hash" json:"password" validate:"required,min=8"`
+	Company      string          `json:"company,omitempty"`
+	Addresses    []CreateAddress `json:"addresses"`
+	DateCreated  *time.Time      `json:"-"`
+	DateModified *time.Time      `json:"-"`
+}
+
+// UpdateUser contains the fields that can be changed on an existing User. A
+// nil field is left untouched, so a caller only has to send the fields it
+// actually wants to change rather than the whole document.
+type UpdateUser struct {
+	UserType  *string `json:"type"`
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Email     *string `json:"email"`
+	Password  *string `json:"password" validate:"omitempty,min=8"`
+	Company   *string `json:"company"`
+}
+
+// IsEmpty reports whether patch leaves every field untouched.
+func (patch *UpdateUser) IsEmpty() bool {
+	return patch.UserType == nil &&
+		patch.FirstName == nil &&
+		patch.LastName == nil &&
+		patch.Email == nil &&
+		patch.Password == nil &&
+		patch.Company == nil
+}
+
+// Sort directions accepted by ListOptions.SortDir.
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// Bounds placed on ListOptions.Limit and ListOptions.Offset to keep List
+// from scanning an unbounded number of documents/rows in one request, even
+// though the page it returns stays small.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+	MaxOffset    = 10000
+)
+
+// ListOptions controls filtering, sorting, paging and free-text search for
+// List.
+type ListOptions struct {
+	Limit   int
+	Offset  int
+	SortBy  string
+	SortDir string
+
+	// Filter matches exact values for a fixed set of fields: user_type,
+	// company, email.
+	Filter map[string]string
+
+	// Query free-text searches across first_name, last_name and email.
+	Query string
+}
+
+// Normalize clamps Limit/Offset to sane bounds and fills in defaults, so
+// callers don't each have to reimplement these checks.
+func (o ListOptions) Normalize() ListOptions {
+	if o.Limit <= 0 {
+		o.Limit = DefaultLimit
+	}
+	if o.Limit > MaxLimit {
+		o.Limit = MaxLimit
+	}
+	if o.Offset < 0 {
+		o.Offset = 0
+	}
+	if o.Offset > MaxOffset {
+		o.Offset = MaxOffset
+	}
+	if o.SortDir != SortAsc && o.SortDir != SortDesc {
+		o.SortDir = SortAsc
+	}
+
+	return o
+}
+
+// PasswordResetExpiry is how long a password-reset token remains valid
+// after RequestPasswordReset issues it.
+const PasswordResetExpiry = time.Hour
+
+// ListResult is the paged response from List.
+type ListResult struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// CreateAddress contains the information needed to create a new Address.
+type CreateAddress struct {
+	Type         string     `json:"type" validate:"required"`
+	LineOne      string     `json:"line_one" validate:"required"`
+	LineTwo      string     `json:"line_two,omitempty"`
+	City         string     `json:"city" validate:"required"`
+	State        string     `json:"state" validate:"required"`
+	Zipcode      string     `json:"zipcode" validate:"required"`
+	Phone        string     `json:"phone,omitempty"`
+	DateCreated  *time.Time `json:"-"`
+	DateModified *time.Time `json:"-"`
+}
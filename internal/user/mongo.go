@@ -0,0 +1,381 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/service/internal/platform/db"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const usersCollection = "users"
+
+// MongoRepository is a UserRepository backed by MongoDB via mgo.
+type MongoRepository struct {
+	dbConn *db.DB
+}
+
+// NewMongoRepository opens a connection to MongoDB and returns a
+// UserRepository backed by it.
+func NewMongoRepository(ctx context.Context, dsn string) (*MongoRepository, error) {
+	dbConn, err := db.New(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "connecting to mongodb")
+	}
+
+	r := &MongoRepository{dbConn: dbConn}
+	if err := r.ensureIndexes(ctx); err != nil {
+		return nil, errors.Wrap(err, "ensuring user indexes")
+	}
+
+	return r, nil
+}
+
+// ensureIndexes creates the indexes List's search and filtering rely on, and
+// the unique index on email that backs duplicate-key detection in Create
+// and Update.
+func (r *MongoRepository) ensureIndexes(ctx context.Context) error {
+	textIndex := mgo.Index{
+		Key: []string{"$text:first_name", "$text:last_name", "$text:email"},
+	}
+
+	emailIndex := mgo.Index{
+		Key:    []string{"email"},
+		Unique: true,
+	}
+
+	f := func(collection *mgo.Collection) error {
+		if err := collection.EnsureIndex(textIndex); err != nil {
+			return err
+		}
+		return collection.EnsureIndex(emailIndex)
+	}
+
+	return r.dbConn.Execute(ctx, usersCollection, f)
+}
+
+// List retrieves a paged, filtered, sorted list of existing users from the
+// database.
+func (r *MongoRepository) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.List")
+	defer span.End()
+
+	q := bson.M{}
+	for _, field := range []string{"user_type", "company", "email"} {
+		if value, ok := opts.Filter[field]; ok {
+			q[mongoFilterField(field)] = value
+		}
+	}
+	if opts.Query != "" {
+		q["$text"] = bson.M{"$search": opts.Query}
+	}
+
+	sortCol, ok := mongoSortColumns[opts.SortBy]
+	if !ok {
+		sortCol = mongoSortColumns[""]
+	}
+	if opts.SortDir == SortDesc {
+		sortCol = "-" + sortCol
+	}
+
+	u := []User{}
+	var total int
+	f := func(collection *mgo.Collection) error {
+		query := collection.Find(q)
+
+		var err error
+		if total, err = query.Count(); err != nil {
+			return err
+		}
+
+		return query.Sort(sortCol).Skip(opts.Offset).Limit(opts.Limit).All(&u)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		return nil, errors.Wrap(err, "db.users.find()")
+	}
+
+	return &ListResult{Users: u, Total: total, Limit: opts.Limit, Offset: opts.Offset}, nil
+}
+
+// mongoFilterField maps a ListOptions.Filter key to the bson field it
+// restricts. Only called for the fixed set of fields List allow-lists, so
+// unlike Filter itself it never needs to reject an unknown key.
+func mongoFilterField(field string) string {
+	switch field {
+	case "user_type":
+		return "type"
+	default:
+		return field
+	}
+}
+
+// mongoSortColumns maps the ListOptions.SortBy values the API exposes to
+// the bson field they sort on, so callers can't sort on arbitrary
+// (possibly sensitive) fields via SortBy.
+var mongoSortColumns = map[string]string{
+	"":              "date_created",
+	"first_name":    "first_name",
+	"last_name":     "last_name",
+	"email":         "email",
+	"company":       "company",
+	"date_created":  "date_created",
+	"date_modified": "date_modified",
+}
+
+// Retrieve gets the specified user from the database.
+func (r *MongoRepository) Retrieve(ctx context.Context, userID string) (*User, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.Retrieve")
+	defer span.End()
+
+	if !bson.IsObjectIdHex(userID) {
+		return nil, errors.Wrapf(ErrInvalidID, "bson.IsObjectIdHex: %s", userID)
+	}
+
+	q := bson.M{"user_id": userID}
+
+	var u *User
+	f := func(collection *mgo.Collection) error {
+		return collection.Find(q).One(&u)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, fmt.Sprintf("db.users.find(%s)", db.Query(q)))
+	}
+
+	return u, nil
+}
+
+// GetByEmail gets the user with the given email address from the database.
+func (r *MongoRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.GetByEmail")
+	defer span.End()
+
+	q := bson.M{"email": email}
+
+	var u *User
+	f := func(collection *mgo.Collection) error {
+		return collection.Find(q).One(&u)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, fmt.Sprintf("db.users.find(%s)", db.Query(q)))
+	}
+
+	return u, nil
+}
+
+// Create inserts a new user into the database.
+func (r *MongoRepository) Create(ctx context.Context, u *User) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.Create")
+	defer span.End()
+
+	f := func(collection *mgo.Collection) error {
+		return collection.Insert(u)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if mgo.IsDup(err) {
+			return ErrDuplicateEmail
+		}
+		return errors.Wrap(err, fmt.Sprintf("db.users.insert(%s)", db.Query(u)))
+	}
+
+	return nil
+}
+
+// Update applies patch to the user document matching userID, touching only
+// the fields patch sets.
+func (r *MongoRepository) Update(ctx context.Context, userID string, patch *UpdateUser, now time.Time) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.Update")
+	defer span.End()
+
+	if !bson.IsObjectIdHex(userID) {
+		return errors.Wrap(ErrInvalidID, "check objectid")
+	}
+
+	q := bson.M{"user_id": userID}
+	m := bson.M{"$set": mongoUpdateSet(patch, now)}
+
+	f := func(collection *mgo.Collection) error {
+		return collection.Update(q, m)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			return ErrNotFound
+		}
+		if mgo.IsDup(err) {
+			return ErrDuplicateEmail
+		}
+		return errors.Wrap(err, fmt.Sprintf("db.users.update(%s, %s)", db.Query(q), db.Query(m)))
+	}
+
+	return nil
+}
+
+// mongoUpdateSet builds the $set document for Update, including only the
+// fields patch actually set.
+func mongoUpdateSet(patch *UpdateUser, now time.Time) bson.M {
+	set := bson.M{"date_modified": now}
+
+	if patch.UserType != nil {
+		set["type"] = *patch.UserType
+	}
+	if patch.FirstName != nil {
+		set["first_name"] = *patch.FirstName
+	}
+	if patch.LastName != nil {
+		set["last_name"] = *patch.LastName
+	}
+	if patch.Email != nil {
+		set["email"] = *patch.Email
+	}
+	if patch.Password != nil {
+		set["password_hash"] = *patch.Password
+	}
+	if patch.Company != nil {
+		set["company"] = *patch.Company
+	}
+
+	return set
+}
+
+// UpdateAddresses replaces the address list on the user document matching
+// userID, without touching any other field.
+func (r *MongoRepository) UpdateAddresses(ctx context.Context, userID string, addresses []Address, now time.Time) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.UpdateAddresses")
+	defer span.End()
+
+	q := bson.M{"user_id": userID}
+	m := bson.M{"$set": bson.M{"addresses": addresses, "date_modified": now}}
+
+	f := func(collection *mgo.Collection) error {
+		return collection.Update(q, m)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			return ErrNotFound
+		}
+		return errors.Wrap(err, fmt.Sprintf("db.users.update(%s, %s)", db.Query(q), db.Query(m)))
+	}
+
+	return nil
+}
+
+// Delete removes a user from the database.
+func (r *MongoRepository) Delete(ctx context.Context, userID string) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.Delete")
+	defer span.End()
+
+	if !bson.IsObjectIdHex(userID) {
+		return errors.Wrapf(ErrInvalidID, "bson.IsObjectIdHex: %s", userID)
+	}
+
+	q := bson.M{"user_id": userID}
+
+	f := func(collection *mgo.Collection) error {
+		return collection.Remove(q)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			return ErrNotFound
+		}
+		return errors.Wrap(err, fmt.Sprintf("db.users.remove(%s)", db.Query(q)))
+	}
+
+	return nil
+}
+
+// SetPasswordHash overwrites just userID's password hash.
+func (r *MongoRepository) SetPasswordHash(ctx context.Context, userID, passwordHash string) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.SetPasswordHash")
+	defer span.End()
+
+	q := bson.M{"user_id": userID}
+	m := bson.M{"$set": bson.M{"password_hash": passwordHash}}
+
+	f := func(collection *mgo.Collection) error {
+		return collection.Update(q, m)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			return ErrNotFound
+		}
+		return errors.Wrap(err, fmt.Sprintf("db.users.update(%s, %s)", db.Query(q), db.Query(m)))
+	}
+
+	return nil
+}
+
+// SetPasswordResetToken records tokenHash and its expiry against userID.
+func (r *MongoRepository) SetPasswordResetToken(ctx context.Context, userID, tokenHash string, expiry time.Time) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.SetPasswordResetToken")
+	defer span.End()
+
+	q := bson.M{"user_id": userID}
+	m := bson.M{"$set": bson.M{"reset_token_hash": tokenHash, "reset_token_expiry": expiry}}
+
+	f := func(collection *mgo.Collection) error {
+		return collection.Update(q, m)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			return ErrNotFound
+		}
+		return errors.Wrap(err, fmt.Sprintf("db.users.update(%s, %s)", db.Query(q), db.Query(m)))
+	}
+
+	return nil
+}
+
+// GetByResetTokenHash finds the user a reset token was issued to.
+func (r *MongoRepository) GetByResetTokenHash(ctx context.Context, tokenHash string) (*User, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.GetByResetTokenHash")
+	defer span.End()
+
+	q := bson.M{"reset_token_hash": tokenHash}
+
+	var u *User
+	f := func(collection *mgo.Collection) error {
+		return collection.Find(q).One(&u)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, fmt.Sprintf("db.users.find(%s)", db.Query(q)))
+	}
+
+	return u, nil
+}
+
+// CompletePasswordReset sets userID's password hash and clears its reset
+// token fields in a single update.
+func (r *MongoRepository) CompletePasswordReset(ctx context.Context, userID, passwordHash string) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.MongoRepository.CompletePasswordReset")
+	defer span.End()
+
+	q := bson.M{"user_id": userID}
+	m := bson.M{
+		"$set":   bson.M{"password_hash": passwordHash},
+		"$unset": bson.M{"reset_token_hash": "", "reset_token_expiry": ""},
+	}
+
+	f := func(collection *mgo.Collection) error {
+		return collection.Update(q, m)
+	}
+	if err := r.dbConn.Execute(ctx, usersCollection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			return ErrNotFound
+		}
+		return errors.Wrap(err, fmt.Sprintf("db.users.update(%s, %s)", db.Query(q), db.Query(m)))
+	}
+
+	return nil
+}
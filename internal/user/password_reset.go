@@ -0,0 +1,127 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetTokenBytes is the amount of entropy in a raw reset token, before
+// hex-encoding.
+const resetTokenBytes = 32
+
+// ChangePassword updates userID's password after verifying oldPassword
+// against the current hash.
+func (s *Service) ChangePassword(ctx context.Context, userID, oldPassword, newPassword string) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.ChangePassword")
+	defer span.End()
+
+	if err := authorizeSelfOrAdmin(ctx, userID); err != nil {
+		return err
+	}
+
+	u, err := s.Repo.Retrieve(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(oldPassword)); err != nil {
+		return errors.Wrap(ErrValidation, "current password is incorrect")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.Wrap(err, "hashing password")
+	}
+
+	if err := s.Repo.SetPasswordHash(ctx, userID, string(hash)); err != nil {
+		return err
+	}
+
+	s.publish(ctx, "user.updated", userID, []string{"password"})
+
+	return nil
+}
+
+// RequestPasswordReset issues a password-reset token for email, storing
+// only its hash, and returns the raw token to be delivered out of band
+// (e.g. emailed). It returns success even if no user has that email, so
+// callers can't use it to enumerate accounts.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) (string, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.RequestPasswordReset")
+	defer span.End()
+
+	u, err := s.Repo.GetByEmail(ctx, email)
+	if err != nil {
+		if err == ErrNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+
+	token, err := newResetToken()
+	if err != nil {
+		return "", errors.Wrap(err, "generating reset token")
+	}
+
+	expiry := time.Now().Add(PasswordResetExpiry)
+	if err := s.Repo.SetPasswordResetToken(ctx, u.UserID, hashResetToken(token), expiry); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ResetPassword looks up the user a still-valid token was issued to and
+// sets newPassword, clearing the reset token so it can't be reused.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.ResetPassword")
+	defer span.End()
+
+	u, err := s.Repo.GetByResetTokenHash(ctx, hashResetToken(token))
+	if err != nil {
+		if err == ErrNotFound {
+			return errors.Wrap(ErrValidation, "reset token is invalid or expired")
+		}
+		return err
+	}
+
+	if u.ResetTokenExpiry == nil || time.Now().After(*u.ResetTokenExpiry) {
+		return errors.Wrap(ErrValidation, "reset token is invalid or expired")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.Wrap(err, "hashing password")
+	}
+
+	if err := s.Repo.CompletePasswordReset(ctx, u.UserID, string(hash)); err != nil {
+		return err
+	}
+
+	s.publish(ctx, "user.updated", u.UserID, []string{"password"})
+
+	return nil
+}
+
+// newResetToken generates a cryptographically random, hex-encoded token.
+func newResetToken() (string, error) {
+	b := make([]byte, resetTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashResetToken hashes a raw reset token for storage/lookup, so the raw
+// token (the bearer credential) never touches the database.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
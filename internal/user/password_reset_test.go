@@ -0,0 +1,84 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestUser(t *testing.T, userID, email, password string) *User {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+
+	return &User{UserID: userID, Email: email, PasswordHash: string(hash), Role: RoleUser}
+}
+
+func TestChangePassword(t *testing.T) {
+	u := newTestUser(t, ownerID, "owner@example.com", "old-password")
+	svc := NewService(newMemoryRepository(u), nil)
+	ctx := authCtx(ownerID, RoleUser)
+
+	if err := svc.ChangePassword(ctx, ownerID, "wrong-password", "new-password"); HTTPStatus(err) != http.StatusBadRequest {
+		t.Fatalf("wrong old password: got %v", err)
+	}
+
+	if err := svc.ChangePassword(ctx, ownerID, "old-password", "new-password"); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte("new-password")); err != nil {
+		t.Fatalf("password was not updated: %v", err)
+	}
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	u := newTestUser(t, ownerID, "owner@example.com", "old-password")
+	svc := NewService(newMemoryRepository(u), nil)
+	ctx := context.Background()
+
+	token, err := svc.RequestPasswordReset(ctx, "owner@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if token == "" {
+		t.Fatal("expected a non-empty reset token")
+	}
+
+	if err := svc.ResetPassword(ctx, "not-the-token", "new-password"); HTTPStatus(err) != http.StatusBadRequest {
+		t.Fatalf("bad token: got %v", err)
+	}
+
+	if err := svc.ResetPassword(ctx, token, "new-password"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte("new-password")); err != nil {
+		t.Fatalf("password was not updated: %v", err)
+	}
+	if u.ResetTokenHash != "" {
+		t.Fatal("expected reset token hash to be cleared")
+	}
+
+	// The token can't be reused.
+	if err := svc.ResetPassword(ctx, token, "another-password"); HTTPStatus(err) != http.StatusBadRequest {
+		t.Fatalf("reused token: got %v", err)
+	}
+}
+
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	svc := NewService(newMemoryRepository(), nil)
+
+	token, err := svc.RequestPasswordReset(context.Background(), "nobody@example.com")
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	if token != "" {
+		t.Fatal("expected no token for an unknown email")
+	}
+}
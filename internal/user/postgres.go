@@ -0,0 +1,405 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+)
+
+// PostgresRepository is a UserRepository backed by PostgreSQL via
+// database/sql, for deployments that would rather not run MongoDB.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository opens a connection to Postgres and returns a
+// UserRepository backed by it.
+func NewPostgresRepository(ctx context.Context, dsn string) (*PostgresRepository, error) {
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening postgres connection")
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "pinging postgres")
+	}
+
+	r := &PostgresRepository{db: sqlDB}
+	if err := r.ensureSchema(ctx); err != nil {
+		return nil, errors.Wrap(err, "ensuring users schema")
+	}
+
+	return r, nil
+}
+
+// ensureSchema creates the users table and the unique index on email that
+// Create and Update rely on to turn a duplicate email into ErrDuplicateEmail,
+// mirroring what ensureIndexes does for MongoRepository.
+func (r *PostgresRepository) ensureSchema(ctx context.Context) error {
+	const createTable = `
+		CREATE TABLE IF NOT EXISTS users (
+			user_id             text PRIMARY KEY,
+			type                text NOT NULL,
+			role                text NOT NULL DEFAULT 'user',
+			first_name          text NOT NULL,
+			last_name           text NOT NULL,
+			email               text NOT NULL,
+			password_hash       text NOT NULL,
+			company             text,
+			addresses           jsonb,
+			date_created        timestamptz,
+			date_modified       timestamptz,
+			reset_token_hash    text,
+			reset_token_expiry  timestamptz
+		)`
+
+	if _, err := r.db.ExecContext(ctx, createTable); err != nil {
+		return errors.Wrap(err, "creating users table")
+	}
+
+	const createEmailIndex = `CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email)`
+	if _, err := r.db.ExecContext(ctx, createEmailIndex); err != nil {
+		return errors.Wrap(err, "creating users email index")
+	}
+
+	return nil
+}
+
+// postgresSortColumns maps the ListOptions.SortBy values the API exposes to
+// the column they sort on, so callers can't inject arbitrary SQL via SortBy.
+var postgresSortColumns = map[string]string{
+	"":              "date_created",
+	"first_name":    "first_name",
+	"last_name":     "last_name",
+	"email":         "email",
+	"company":       "company",
+	"date_created":  "date_created",
+	"date_modified": "date_modified",
+}
+
+// List retrieves a paged, filtered, sorted list of existing users from the
+// database.
+func (r *PostgresRepository) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.List")
+	defer span.End()
+
+	where, args := postgresListWhere(opts)
+
+	var total int
+	countQ := `SELECT count(*) FROM users` + where
+	if err := r.db.QueryRowContext(ctx, countQ, args...).Scan(&total); err != nil {
+		return nil, errors.Wrap(err, "db.users count")
+	}
+
+	sortCol, ok := postgresSortColumns[opts.SortBy]
+	if !ok {
+		sortCol = postgresSortColumns[""]
+	}
+	sortDir := "ASC"
+	if opts.SortDir == SortDesc {
+		sortDir = "DESC"
+	}
+
+	q := `SELECT ` + userColumns + `
+		FROM users` + where + fmt.Sprintf(" ORDER BY %s %s LIMIT $%d OFFSET $%d", sortCol, sortDir, len(args)+1, len(args)+2)
+	args = append(args, opts.Limit, opts.Offset)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "db.users select")
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning user row")
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating user rows")
+	}
+
+	return &ListResult{Users: users, Total: total, Limit: opts.Limit, Offset: opts.Offset}, nil
+}
+
+// postgresListWhere builds a WHERE clause (and its args) from the filter and
+// free-text query in opts.
+func postgresListWhere(opts ListOptions) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	for _, field := range []string{"user_type", "company", "email"} {
+		value, ok := opts.Filter[field]
+		if !ok {
+			continue
+		}
+		args = append(args, value)
+		column := field
+		if field == "user_type" {
+			column = "type"
+		}
+		clauses = append(clauses, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if opts.Query != "" {
+		args = append(args, "%"+opts.Query+"%")
+		n := len(args)
+		clauses = append(clauses, fmt.Sprintf("(first_name ILIKE $%d OR last_name ILIKE $%d OR email ILIKE $%d)", n, n, n))
+	}
+
+	if len(clauses) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// Retrieve gets the specified user from the database.
+func (r *PostgresRepository) Retrieve(ctx context.Context, userID string) (*User, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.Retrieve")
+	defer span.End()
+
+	return r.one(ctx, "user_id = $1", userID)
+}
+
+// GetByEmail gets the user with the given email address from the database.
+func (r *PostgresRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.GetByEmail")
+	defer span.End()
+
+	return r.one(ctx, "email = $1", email)
+}
+
+// userColumns lists the user columns selected by List and one, in the order
+// scanUser expects them.
+const userColumns = "user_id, type, role, first_name, last_name, email, password_hash, company, addresses, date_created, date_modified"
+
+func (r *PostgresRepository) one(ctx context.Context, where string, arg interface{}) (*User, error) {
+	q := `SELECT ` + userColumns + `
+		FROM users WHERE ` + where
+
+	u, err := scanUser(r.db.QueryRowContext(ctx, q, arg))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	var u User
+	var addresses []byte
+	if err := row.Scan(&u.UserID, &u.UserType, &u.Role, &u.FirstName, &u.LastName, &u.Email, &u.PasswordHash, &u.Company, &addresses, &u.DateCreated, &u.DateModified); err != nil {
+		return nil, err
+	}
+	if len(addresses) > 0 {
+		if err := json.Unmarshal(addresses, &u.Addresses); err != nil {
+			return nil, errors.Wrap(err, "unmarshalling addresses")
+		}
+	}
+
+	return &u, nil
+}
+
+// Create inserts a new user into the database.
+func (r *PostgresRepository) Create(ctx context.Context, u *User) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.Create")
+	defer span.End()
+
+	addresses, err := json.Marshal(u.Addresses)
+	if err != nil {
+		return errors.Wrap(err, "marshalling addresses")
+	}
+
+	const q = `INSERT INTO users (user_id, type, role, first_name, last_name, email, password_hash, company, addresses, date_created, date_modified)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err = r.db.ExecContext(ctx, q, u.UserID, u.UserType, u.Role, u.FirstName, u.LastName, u.Email, u.PasswordHash, u.Company, addresses, u.DateCreated, u.DateModified)
+	if isUniqueViolation(err) {
+		return ErrDuplicateEmail
+	}
+	return errors.Wrap(err, "db.users insert")
+}
+
+// isUniqueViolation reports whether err is a Postgres unique-constraint
+// violation, as raised by the unique index on email.
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code.Name() == "unique_violation"
+}
+
+// Update applies patch to the user row matching userID, touching only the
+// columns patch actually sets.
+func (r *PostgresRepository) Update(ctx context.Context, userID string, patch *UpdateUser, now time.Time) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.Update")
+	defer span.End()
+
+	args := []interface{}{userID}
+	set := func(column string, value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("%s = $%d", column, len(args))
+	}
+
+	clauses := []string{set("date_modified", now)}
+	if patch.UserType != nil {
+		clauses = append(clauses, set("type", *patch.UserType))
+	}
+	if patch.FirstName != nil {
+		clauses = append(clauses, set("first_name", *patch.FirstName))
+	}
+	if patch.LastName != nil {
+		clauses = append(clauses, set("last_name", *patch.LastName))
+	}
+	if patch.Email != nil {
+		clauses = append(clauses, set("email", *patch.Email))
+	}
+	if patch.Password != nil {
+		clauses = append(clauses, set("password_hash", *patch.Password))
+	}
+	if patch.Company != nil {
+		clauses = append(clauses, set("company", *patch.Company))
+	}
+
+	q := `UPDATE users SET ` + strings.Join(clauses, ", ") + ` WHERE user_id = $1`
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateEmail
+		}
+		return errors.Wrap(err, "db.users update")
+	}
+
+	return rowsAffectedOrNotFound(res)
+}
+
+// UpdateAddresses replaces the address list on the user row matching
+// userID, without touching any other column.
+func (r *PostgresRepository) UpdateAddresses(ctx context.Context, userID string, addresses []Address, now time.Time) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.UpdateAddresses")
+	defer span.End()
+
+	data, err := json.Marshal(addresses)
+	if err != nil {
+		return errors.Wrap(err, "marshalling addresses")
+	}
+
+	const q = `UPDATE users SET addresses = $2, date_modified = $3 WHERE user_id = $1`
+
+	res, err := r.db.ExecContext(ctx, q, userID, data, now)
+	if err != nil {
+		return errors.Wrap(err, "db.users update")
+	}
+
+	return rowsAffectedOrNotFound(res)
+}
+
+// Delete removes a user from the database.
+func (r *PostgresRepository) Delete(ctx context.Context, userID string) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.Delete")
+	defer span.End()
+
+	const q = `DELETE FROM users WHERE user_id = $1`
+
+	res, err := r.db.ExecContext(ctx, q, userID)
+	if err != nil {
+		return errors.Wrap(err, "db.users delete")
+	}
+
+	return rowsAffectedOrNotFound(res)
+}
+
+func rowsAffectedOrNotFound(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "checking rows affected")
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetPasswordHash overwrites just userID's password hash.
+func (r *PostgresRepository) SetPasswordHash(ctx context.Context, userID, passwordHash string) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.SetPasswordHash")
+	defer span.End()
+
+	const q = `UPDATE users SET password_hash = $2 WHERE user_id = $1`
+
+	res, err := r.db.ExecContext(ctx, q, userID, passwordHash)
+	if err != nil {
+		return errors.Wrap(err, "db.users update")
+	}
+
+	return rowsAffectedOrNotFound(res)
+}
+
+// SetPasswordResetToken records tokenHash and its expiry against userID.
+func (r *PostgresRepository) SetPasswordResetToken(ctx context.Context, userID, tokenHash string, expiry time.Time) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.SetPasswordResetToken")
+	defer span.End()
+
+	const q = `UPDATE users SET reset_token_hash = $2, reset_token_expiry = $3 WHERE user_id = $1`
+
+	res, err := r.db.ExecContext(ctx, q, userID, tokenHash, expiry)
+	if err != nil {
+		return errors.Wrap(err, "db.users update")
+	}
+
+	return rowsAffectedOrNotFound(res)
+}
+
+// GetByResetTokenHash finds the user a reset token was issued to.
+func (r *PostgresRepository) GetByResetTokenHash(ctx context.Context, tokenHash string) (*User, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.GetByResetTokenHash")
+	defer span.End()
+
+	u, err := r.one(ctx, "reset_token_hash = $1", tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// one doesn't select the reset token columns, so load the expiry
+	// ResetPassword checks separately.
+	const q = `SELECT reset_token_expiry FROM users WHERE user_id = $1`
+	if err := r.db.QueryRowContext(ctx, q, u.UserID).Scan(&u.ResetTokenExpiry); err != nil {
+		return nil, errors.Wrap(err, "db.users select reset_token_expiry")
+	}
+	u.ResetTokenHash = tokenHash
+
+	return u, nil
+}
+
+// CompletePasswordReset sets userID's password hash and clears its reset
+// token fields in a single update.
+func (r *PostgresRepository) CompletePasswordReset(ctx context.Context, userID, passwordHash string) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.PostgresRepository.CompletePasswordReset")
+	defer span.End()
+
+	const q = `UPDATE users SET password_hash = $2, reset_token_hash = NULL, reset_token_expiry = NULL WHERE user_id = $1`
+
+	res, err := r.db.ExecContext(ctx, q, userID, passwordHash)
+	if err != nil {
+		return errors.Wrap(err, "db.users update")
+	}
+
+	return rowsAffectedOrNotFound(res)
+}
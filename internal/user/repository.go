@@ -0,0 +1,75 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// UserRepository abstracts the storage backend used to persist Users so the
+// service layer in this package can be exercised against more than one
+// database and so handlers can swap in a fake for testing.
+type UserRepository interface {
+	List(ctx context.Context, opts ListOptions) (*ListResult, error)
+	Retrieve(ctx context.Context, userID string) (*User, error)
+	Create(ctx context.Context, u *User) error
+
+	// Update applies patch to userID, touching only the fields patch sets,
+	// and stamps date_modified with now.
+	Update(ctx context.Context, userID string, patch *UpdateUser, now time.Time) error
+
+	// UpdateAddresses replaces userID's whole address list in one update, so
+	// callers mutating addresses don't have to go through Update's
+	// field-by-field patch for a value that's naturally all-or-nothing.
+	UpdateAddresses(ctx context.Context, userID string, addresses []Address, now time.Time) error
+
+	Delete(ctx context.Context, userID string) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+
+	// SetPasswordHash overwrites just userID's password hash, without
+	// touching any other field.
+	SetPasswordHash(ctx context.Context, userID, passwordHash string) error
+
+	// SetPasswordResetToken records tokenHash and its expiry against
+	// userID, for RequestPasswordReset.
+	SetPasswordResetToken(ctx context.Context, userID, tokenHash string, expiry time.Time) error
+
+	// GetByResetTokenHash finds the user a reset token was issued to,
+	// including its ResetTokenExpiry. It matches on the hash only; checking
+	// that the token hasn't expired is left to the caller.
+	GetByResetTokenHash(ctx context.Context, tokenHash string) (*User, error)
+
+	// CompletePasswordReset sets userID's password hash and clears its
+	// reset token fields in a single update.
+	CompletePasswordReset(ctx context.Context, userID, passwordHash string) error
+}
+
+// Driver identifies which UserRepository implementation to construct.
+type Driver string
+
+// Supported repository drivers.
+const (
+	DriverMongo    Driver = "mongodb"
+	DriverPostgres Driver = "postgres"
+)
+
+// Config holds the connection details needed to wire up a UserRepository at
+// startup.
+type Config struct {
+	Driver Driver
+	DSN    string
+}
+
+// NewRepository constructs the UserRepository implementation named by
+// cfg.Driver, connecting to cfg.DSN.
+func NewRepository(ctx context.Context, cfg Config) (UserRepository, error) {
+	switch cfg.Driver {
+	case DriverMongo:
+		return NewMongoRepository(ctx, cfg.DSN)
+	case DriverPostgres:
+		return NewPostgresRepository(ctx, cfg.DSN)
+	default:
+		return nil, errors.Errorf("unknown user repository driver %q", cfg.Driver)
+	}
+}
@@ -2,45 +2,60 @@ package user
 
 import (
 	"context"
-	"fmt"
 	"time"
 
-	"github.com/ardanlabs/service/internal/platform/db"
+	"github.com/ardanlabs/service/internal/platform/auth"
+	"github.com/ardanlabs/service/internal/platform/events"
 	"github.com/pkg/errors"
 	"go.opencensus.io/trace"
-	mgo "gopkg.in/mgo.v2"
+	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/mgo.v2/bson"
 )
 
-const usersCollection = "users"
+// tokenExpiry is how long a token issued by Authenticate remains valid.
+const tokenExpiry = 72 * time.Hour
 
-var (
-	// ErrNotFound is abstracting the mgo not found error.
-	ErrNotFound = errors.New("Entity not found")
+// anonymousActor names the actor recorded on events emitted by operations
+// that can happen before a caller is authenticated, such as self-registration.
+const anonymousActor = "anonymous"
 
-	// ErrInvalidID occurs when an ID is not in a valid form.
-	ErrInvalidID = errors.New("ID is not in its proper form")
-)
+// Service wires a UserRepository together with an events.Publisher so the
+// operations below can both persist Users and announce what happened to
+// them. Construct one with NewService rather than composing the struct
+// literal directly, so a nil Publisher is never dereferenced.
+type Service struct {
+	Repo      UserRepository
+	Publisher events.Publisher
+}
+
+// NewService returns a Service backed by repo, publishing to publisher. A
+// nil publisher is replaced with events.NoopPublisher{}, so callers that
+// don't care about events can pass nil.
+func NewService(repo UserRepository, publisher events.Publisher) *Service {
+	if publisher == nil {
+		publisher = events.NoopPublisher{}
+	}
+
+	return &Service{Repo: repo, Publisher: publisher}
+}
 
-// List retrieves a list of existing users from the database.
-func List(ctx context.Context, dbConn *db.DB) ([]User, error) {
+// List retrieves a paged, filtered, sorted list of existing users. Only
+// admins may list arbitrary users.
+func (s *Service) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
 	ctx, span := trace.StartSpan(ctx, "internal.user.List")
 	defer span.End()
 
-	u := []User{}
-
-	f := func(collection *mgo.Collection) error {
-		return collection.Find(nil).All(&u)
-	}
-	if err := dbConn.Execute(ctx, usersCollection, f); err != nil {
-		return nil, errors.Wrap(err, "db.users.find()")
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok || !claims.HasRole(RoleAdmin) {
+		return nil, ErrForbidden
 	}
 
-	return u, nil
+	return s.Repo.List(ctx, opts.Normalize())
 }
 
-// Retrieve gets the specified user from the database.
-func Retrieve(ctx context.Context, dbConn *db.DB, userID string) (*User, error) {
+// Retrieve gets the specified user. Non-admins may only retrieve their own
+// record.
+func (s *Service) Retrieve(ctx context.Context, userID string) (*User, error) {
 	ctx, span := trace.StartSpan(ctx, "internal.user.Retrieve")
 	defer span.End()
 
@@ -48,33 +63,31 @@ func Retrieve(ctx context.Context, dbConn *db.DB, userID string) (*User, error)
 		return nil, errors.Wrapf(ErrInvalidID, "bson.IsObjectIdHex: %s", userID)
 	}
 
-	q := bson.M{"user_id": userID}
-
-	var u *User
-	f := func(collection *mgo.Collection) error {
-		return collection.Find(q).One(&u)
-	}
-	if err := dbConn.Execute(ctx, usersCollection, f); err != nil {
-		if err == mgo.ErrNotFound {
-			return nil, ErrNotFound
-		}
-		return nil, errors.Wrap(err, fmt.Sprintf("db.users.find(%s)", db.Query(q)))
+	if err := authorizeSelfOrAdmin(ctx, userID); err != nil {
+		return nil, err
 	}
 
-	return u, nil
+	return s.Repo.Retrieve(ctx, userID)
 }
 
-// Create inserts a new user into the database.
-func Create(ctx context.Context, dbConn *db.DB, cu *CreateUser, now time.Time) (*User, error) {
+// Create inserts a new user and publishes a user.created event.
+func (s *Service) Create(ctx context.Context, cu *CreateUser, now time.Time) (*User, error) {
 	ctx, span := trace.StartSpan(ctx, "internal.user.Create")
 	defer span.End()
 
+	hash, err := bcrypt.GenerateFromPassword([]byte(cu.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.Wrap(err, "hashing password")
+	}
+
 	u := User{
 		UserID:       bson.NewObjectId().Hex(),
 		UserType:     cu.UserType,
+		Role:         RoleUser,
 		FirstName:    cu.FirstName,
 		LastName:     cu.LastName,
 		Email:        cu.Email,
+		PasswordHash: string(hash),
 		Company:      cu.Company,
 		DateCreated:  &now,
 		DateModified: &now,
@@ -95,18 +108,19 @@ func Create(ctx context.Context, dbConn *db.DB, cu *CreateUser, now time.Time) (
 		}
 	}
 
-	f := func(collection *mgo.Collection) error {
-		return collection.Insert(&u)
-	}
-	if err := dbConn.Execute(ctx, usersCollection, f); err != nil {
-		return nil, errors.Wrap(err, fmt.Sprintf("db.users.insert(%s)", db.Query(&u)))
+	if err := s.Repo.Create(ctx, &u); err != nil {
+		return nil, err
 	}
 
+	s.publish(ctx, "user.created", u.UserID, nil)
+
 	return &u, nil
 }
 
-// Update replaces a user document in the database.
-func Update(ctx context.Context, dbConn *db.DB, userID string, cu *CreateUser, now time.Time) error {
+// Update applies patch to userID, touching only the fields patch sets.
+// Non-admins may only update their own record. It publishes a user.updated
+// event listing which fields changed.
+func (s *Service) Update(ctx context.Context, userID string, patch *UpdateUser, now time.Time) error {
 	ctx, span := trace.StartSpan(ctx, "internal.user.Update")
 	defer span.End()
 
@@ -114,47 +128,182 @@ func Update(ctx context.Context, dbConn *db.DB, userID string, cu *CreateUser, n
 		return errors.Wrap(ErrInvalidID, "check objectid")
 	}
 
-	cu.DateModified = &now
-	for _, cua := range cu.Addresses {
-		cua.DateModified = &now
+	if patch.IsEmpty() {
+		return errors.Wrap(ErrValidation, "at least one field must be changed")
 	}
 
-	q := bson.M{"user_id": userID}
-	m := bson.M{"$set": cu}
+	if err := authorizeSelfOrAdmin(ctx, userID); err != nil {
+		return err
+	}
+
+	if patch.Password != nil {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*patch.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return errors.Wrap(err, "hashing password")
+		}
+		hashed := string(hash)
+		patch.Password = &hashed
+	}
 
-	f := func(collection *mgo.Collection) error {
-		return collection.Update(q, m)
+	if err := s.Repo.Update(ctx, userID, patch, now); err != nil {
+		return err
 	}
-	if err := dbConn.Execute(ctx, usersCollection, f); err != nil {
-		if err == mgo.ErrNotFound {
-			return ErrNotFound
+
+	s.publish(ctx, "user.updated", userID, changedFields(patch))
+
+	return nil
+}
+
+// UpdateAddresses replaces userID's whole address list. Non-admins may only
+// update their own record. It publishes a user.updated event.
+func (s *Service) UpdateAddresses(ctx context.Context, userID string, cas []CreateAddress, now time.Time) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.UpdateAddresses")
+	defer span.End()
+
+	if !bson.IsObjectIdHex(userID) {
+		return errors.Wrap(ErrInvalidID, "check objectid")
+	}
+
+	if err := authorizeSelfOrAdmin(ctx, userID); err != nil {
+		return err
+	}
+
+	addresses := make([]Address, len(cas))
+	for i, ca := range cas {
+		addresses[i] = Address{
+			Type:         ca.Type,
+			LineOne:      ca.LineOne,
+			LineTwo:      ca.LineTwo,
+			City:         ca.City,
+			State:        ca.State,
+			Zipcode:      ca.Zipcode,
+			Phone:        ca.Phone,
+			DateCreated:  &now,
+			DateModified: &now,
 		}
-		return errors.Wrap(err, fmt.Sprintf("db.customers.update(%s, %s)", db.Query(q), db.Query(m)))
 	}
 
+	if err := s.Repo.UpdateAddresses(ctx, userID, addresses, now); err != nil {
+		return err
+	}
+
+	s.publish(ctx, "user.updated", userID, []string{"addresses"})
+
 	return nil
 }
 
-// Delete removes a user from the database.
-func Delete(ctx context.Context, dbConn *db.DB, userID string) error {
-	ctx, span := trace.StartSpan(ctx, "internal.user.Update")
+// Delete removes a user. Only admins may delete arbitrary users; non-admins
+// may only delete their own record. It publishes a user.deleted event.
+func (s *Service) Delete(ctx context.Context, userID string) error {
+	ctx, span := trace.StartSpan(ctx, "internal.user.Delete")
 	defer span.End()
 
 	if !bson.IsObjectIdHex(userID) {
 		return errors.Wrapf(ErrInvalidID, "bson.IsObjectIdHex: %s", userID)
 	}
 
-	q := bson.M{"user_id": userID}
+	if err := authorizeSelfOrAdmin(ctx, userID); err != nil {
+		return err
+	}
 
-	f := func(collection *mgo.Collection) error {
-		return collection.Remove(q)
+	if err := s.Repo.Delete(ctx, userID); err != nil {
+		return err
 	}
-	if err := dbConn.Execute(ctx, usersCollection, f); err != nil {
-		if err == mgo.ErrNotFound {
-			return ErrNotFound
+
+	s.publish(ctx, "user.deleted", userID, nil)
+
+	return nil
+}
+
+// GetByEmail gets the user with the given email address.
+func (s *Service) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.GetByEmail")
+	defer span.End()
+
+	return s.Repo.GetByEmail(ctx, email)
+}
+
+// Authenticate verifies the email/password pair and, if they match, returns
+// the auth.Claims to issue the user a token for.
+func (s *Service) Authenticate(ctx context.Context, email, password string, now time.Time) (auth.Claims, error) {
+	ctx, span := trace.StartSpan(ctx, "internal.user.Authenticate")
+	defer span.End()
+
+	u, err := s.Repo.GetByEmail(ctx, email)
+	if err != nil {
+		if err == ErrNotFound {
+			return auth.Claims{}, ErrNotFound
 		}
-		return errors.Wrap(err, fmt.Sprintf("db.users.remove(%s)", db.Query(q)))
+		return auth.Claims{}, err
 	}
 
-	return nil
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return auth.Claims{}, ErrNotFound
+	}
+
+	return auth.NewClaims(u.UserID, []string{u.Role}, now, tokenExpiry), nil
+}
+
+// publish fills in the parts of an event common to every user operation and
+// hands it to the Publisher, swallowing the error: a downstream consumer
+// being unreachable shouldn't fail the request that triggered the event.
+func (s *Service) publish(ctx context.Context, eventType, userID string, changedFields []string) {
+	s.Publisher.Publish(ctx, events.Event{
+		Type:          eventType,
+		EntityID:      userID,
+		Timestamp:     time.Now().Unix(),
+		Actor:         actor(ctx),
+		ChangedFields: changedFields,
+	})
+}
+
+// actor names the caller an event should be attributed to, falling back to
+// anonymousActor for operations (like self-registration) that can happen
+// before a caller is authenticated.
+func actor(ctx context.Context) string {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return anonymousActor
+	}
+	return claims.Subject
+}
+
+// changedFields lists the fields patch actually set.
+func changedFields(patch *UpdateUser) []string {
+	var fields []string
+
+	if patch.UserType != nil {
+		fields = append(fields, "type")
+	}
+	if patch.FirstName != nil {
+		fields = append(fields, "first_name")
+	}
+	if patch.LastName != nil {
+		fields = append(fields, "last_name")
+	}
+	if patch.Email != nil {
+		fields = append(fields, "email")
+	}
+	if patch.Password != nil {
+		fields = append(fields, "password")
+	}
+	if patch.Company != nil {
+		fields = append(fields, "company")
+	}
+
+	return fields
+}
+
+// authorizeSelfOrAdmin returns ErrForbidden unless the claims on ctx belong
+// to userID or to an admin.
+func authorizeSelfOrAdmin(ctx context.Context, userID string) error {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return ErrForbidden
+	}
+	if claims.HasRole(RoleAdmin) || claims.Subject == userID {
+		return nil
+	}
+
+	return ErrForbidden
 }